@@ -0,0 +1,50 @@
+package vikebot
+
+import "encoding/json"
+
+// Codec serializes packets sent to, and deserializes packets received from,
+// the game server. Which Codec is active is negotiated once during the
+// clienthello/serverhello handshake (see Game.handshake) and then used for
+// every packet on that connection.
+type Codec interface {
+	// Name identifies the codec during handshake negotiation, e.g. "json"
+	// or "msgpack".
+	Name() string
+	// Binary reports whether the codec's output is non-text. Binary codecs
+	// skip the legacy base64 encoding and switch the transport to
+	// length-prefixed framing instead of newline-delimited text.
+	Binary() bool
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte, v interface{}) error
+}
+
+// jsonCodec is the original, text-based codec. It is always used for the
+// login/clienthello exchange itself, since no codec has been negotiated yet
+// at that point, and remains the default for servers that don't respond
+// with a codec upgrade.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                          { return "json" }
+func (jsonCodec) Binary() bool                           { return false }
+func (jsonCodec) Encode(v interface{}) ([]byte, error)   { return json.Marshal(v) }
+func (jsonCodec) Decode(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// supportedCodecs lists the codecs advertised to the server during
+// clienthello, in order of preference.
+var supportedCodecs = []Codec{msgpackCodec{}, jsonCodec{}}
+
+func codecNames() (names []string) {
+	for _, c := range supportedCodecs {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+func codecByName(name string) Codec {
+	for _, c := range supportedCodecs {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return jsonCodec{}
+}
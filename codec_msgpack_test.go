@@ -0,0 +1,36 @@
+package vikebot
+
+import "testing"
+
+func TestMsgpackCodecRoundTrip(t *testing.T) {
+	pc := uint32(7)
+	want := outPacket{Type: "move", Pc: &pc, Obj: moveObj{Direction: DirectionNorth}}
+
+	c := msgpackCodec{}
+	buf, err := c.Encode(want)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var got outPacket
+	if err := c.Decode(buf, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if got.Type != want.Type {
+		t.Errorf("Type = %q, want %q", got.Type, want.Type)
+	}
+	if got.Pc == nil || *got.Pc != pc {
+		t.Errorf("Pc = %v, want %d", got.Pc, pc)
+	}
+}
+
+func TestMsgpackCodecIdentifiesAsBinary(t *testing.T) {
+	c := msgpackCodec{}
+	if c.Name() != "msgpack" {
+		t.Errorf("Name() = %q, want msgpack", c.Name())
+	}
+	if !c.Binary() {
+		t.Error("Binary() = false, want true")
+	}
+}
@@ -0,0 +1,168 @@
+package vikebot
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Compressor (de)compresses the plaintext of a packet before it is
+// encrypted, trading CPU for bandwidth. It is most useful for Watch, whose
+// HealthMatrix grows quadratically with the map size. Compressors are
+// negotiated during the agreeconn exchange and registered with
+// RegisterCompressor; "snappy" and "lz4" are registered by default.
+type Compressor interface {
+	Name() string
+	Compress(plain []byte) ([]byte, error)
+	Decompress(compressed []byte) ([]byte, error)
+}
+
+// compressMsg/plainMsg are the one-byte type tags prefixed to every sealed
+// frame once compression has been negotiated, mirroring the compressMsg
+// wrapping in hashicorp/memberlist. They let the receiver tell whether a
+// particular frame needs decompressing even though compression was
+// negotiated for the connection as a whole, since seal sends a packet
+// uncompressed when compressing it fails or doesn't make it smaller.
+const (
+	plainMsg byte = iota
+	compressMsg
+)
+
+var compressorRegistry []Compressor
+
+// RegisterCompressor makes a Compressor available for negotiation during
+// Join. Re-registering a name already present replaces it.
+func RegisterCompressor(c Compressor) {
+	for i, existing := range compressorRegistry {
+		if existing.Name() == c.Name() {
+			compressorRegistry[i] = c
+			return
+		}
+	}
+	compressorRegistry = append(compressorRegistry, c)
+}
+
+func init() {
+	RegisterCompressor(lz4Compressor{})
+	RegisterCompressor(snappyCompressor{})
+}
+
+func compressorNames() []string {
+	names := make([]string, len(compressorRegistry))
+	for i, c := range compressorRegistry {
+		names[i] = c.Name()
+	}
+	return names
+}
+
+func compressorByName(name string) Compressor {
+	for _, c := range compressorRegistry {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) Name() string                          { return "snappy" }
+func (snappyCompressor) Compress(plain []byte) ([]byte, error) { return snappy.Encode(nil, plain), nil }
+func (snappyCompressor) Decompress(compressed []byte) ([]byte, error) {
+	return snappy.Decode(nil, compressed)
+}
+
+type lz4Compressor struct{}
+
+func (lz4Compressor) Name() string { return "lz4" }
+
+func (lz4Compressor) Compress(plain []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := lz4.NewWriter(buf)
+	if _, err := w.Write(plain); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (lz4Compressor) Decompress(compressed []byte) ([]byte, error) {
+	return ioutil.ReadAll(lz4.NewReader(bytes.NewReader(compressed)))
+}
+
+// seal encrypts plain and, once compression has been negotiated
+// (g.compressionEnabled), additionally compresses it and prefixes the
+// result with a compressMsg/plainMsg tag. Connections that never negotiated
+// compression keep the exact legacy wire format.
+func (g *Game) seal(plain []byte) ([]byte, error) {
+	if !g.compressionEnabled {
+		if g.codec.Binary() {
+			return g.encrypt(plain)
+		}
+		return g.encrypt64(plain)
+	}
+
+	tag := plainMsg
+	payload := plain
+	if compressed, err := g.compressor.Compress(plain); err == nil && len(compressed) < len(plain) {
+		payload = compressed
+		tag = compressMsg
+	}
+
+	cipherBuf, err := g.encrypt(payload)
+	if err != nil {
+		return nil, err
+	}
+	tagged := append([]byte{tag}, cipherBuf...)
+
+	if g.codec.Binary() {
+		return tagged, nil
+	}
+
+	b64 := make([]byte, base64.RawStdEncoding.EncodedLen(len(tagged)))
+	base64.RawStdEncoding.Encode(b64, tagged)
+	return b64, nil
+}
+
+// open reverses seal.
+func (g *Game) open(buf []byte) ([]byte, error) {
+	if !g.compressionEnabled {
+		if g.codec.Binary() {
+			return g.decrypt(buf)
+		}
+		return g.decrypt64(buf)
+	}
+
+	tagged := buf
+	if !g.codec.Binary() {
+		decoded := make([]byte, base64.RawStdEncoding.DecodedLen(len(buf)))
+		n, err := base64.RawStdEncoding.Decode(decoded, buf)
+		if err != nil {
+			return nil, err
+		}
+		tagged = decoded[:n]
+	}
+	if len(tagged) == 0 {
+		return nil, errors.New("vikebot: empty frame")
+	}
+
+	plain, err := g.decrypt(tagged[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	if tagged[0] == compressMsg {
+		plain, err = g.compressor.Decompress(plain)
+		if err != nil {
+			return nil, fmt.Errorf("vikebot: %s", err.Error())
+		}
+	}
+	return plain, nil
+}
@@ -0,0 +1,47 @@
+package vikebot
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressorRoundTrip(t *testing.T) {
+	plain := []byte("the quick brown fox jumps over the lazy dog, repeatedly, repeatedly, repeatedly")
+
+	for _, c := range []Compressor{snappyCompressor{}, lz4Compressor{}} {
+		t.Run(c.Name(), func(t *testing.T) {
+			compressed, err := c.Compress(plain)
+			if err != nil {
+				t.Fatalf("Compress: %v", err)
+			}
+			got, err := c.Decompress(compressed)
+			if err != nil {
+				t.Fatalf("Decompress: %v", err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Fatalf("got %q, want %q", got, plain)
+			}
+		})
+	}
+}
+
+func TestCompressorByName(t *testing.T) {
+	if compressorByName("snappy") == nil {
+		t.Error(`compressorByName("snappy") = nil, want the registered snappyCompressor`)
+	}
+	if compressorByName("lz4") == nil {
+		t.Error(`compressorByName("lz4") = nil, want the registered lz4Compressor`)
+	}
+	if compressorByName("does-not-exist") != nil {
+		t.Error(`compressorByName("does-not-exist") = non-nil, want nil`)
+	}
+}
+
+func TestRegisterCompressorReplacesByName(t *testing.T) {
+	before := len(compressorRegistry)
+
+	RegisterCompressor(snappyCompressor{})
+	if len(compressorRegistry) != before {
+		t.Fatalf("re-registering an existing name changed the registry size: %d -> %d", before, len(compressorRegistry))
+	}
+}
@@ -0,0 +1,160 @@
+package vikebot
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// dispatchedFrame is what the background read loop hands a waiting
+// trivialActionRespAsync call: either the matching response packet, or the
+// error that ended the read loop.
+type dispatchedFrame struct {
+	buf []byte
+	err error
+}
+
+// nextPc atomically increments g.pc and returns the new value. Every pc
+// mutation goes through this (or setPc), since once the dispatch loop is
+// running, Player actions can be called concurrently with it from any
+// number of user goroutines, and a bare g.pc++ would race.
+func (g *Game) nextPc() uint32 {
+	g.pcMu.Lock()
+	defer g.pcMu.Unlock()
+	g.pc++
+	return g.pc
+}
+
+// setPc overwrites g.pc, for when the server hands the client a fresh
+// counter value (initialpc, resumeconn) instead of incrementing it.
+func (g *Game) setPc(pc uint32) {
+	g.pcMu.Lock()
+	g.pc = pc
+	g.pcMu.Unlock()
+}
+
+// currentPc returns the current pc value.
+func (g *Game) currentPc() uint32 {
+	g.pcMu.Lock()
+	defer g.pcMu.Unlock()
+	return g.pc
+}
+
+// startDispatch starts the background read loop and switches
+// trivialActionResp over to routing through it. Called once Join's
+// handshake and initial map fetch - which both need a plain, synchronous
+// connection - have completed.
+func (g *Game) startDispatch() {
+	g.dispatching = true
+	go g.dispatchLoop()
+}
+
+// dispatchLoop continuously reads frames off the connection, routing
+// responses back to the trivialActionRespAsync call that's waiting for
+// them (matched by pc) and everything else to Events()/On. Close() waits on
+// g.done before touching gcm/Encrypted/Player, so this goroutine is
+// guaranteed to have stopped reading g's connection state by the time
+// Close() mutates it.
+func (g *Game) dispatchLoop() {
+	defer close(g.done)
+	for {
+		pt, buf, err := g.read(true)
+		if err != nil {
+			g.failWaiters(err)
+			close(g.events)
+			return
+		}
+
+		var resp response
+		if g.decodePacket(buf, &resp) == nil && resp.Pc != nil {
+			if waiter, ok := g.takeWaiter(*resp.Pc); ok {
+				waiter <- dispatchedFrame{buf: buf}
+				continue
+			}
+		}
+
+		g.dispatchEvent(pt, buf)
+	}
+}
+
+func (g *Game) registerWaiter(pc uint32) chan dispatchedFrame {
+	ch := make(chan dispatchedFrame, 1)
+	g.waitersMu.Lock()
+	g.respWaiters[pc] = ch
+	g.waitersMu.Unlock()
+	return ch
+}
+
+func (g *Game) takeWaiter(pc uint32) (chan dispatchedFrame, bool) {
+	g.waitersMu.Lock()
+	defer g.waitersMu.Unlock()
+	ch, ok := g.respWaiters[pc]
+	if ok {
+		delete(g.respWaiters, pc)
+	}
+	return ch, ok
+}
+
+func (g *Game) failWaiters(err error) {
+	g.waitersMu.Lock()
+	defer g.waitersMu.Unlock()
+	for pc, ch := range g.respWaiters {
+		ch <- dispatchedFrame{err: err}
+		delete(g.respWaiters, pc)
+	}
+}
+
+// trivialActionRespAsync is the dispatch-loop-aware counterpart of
+// trivialActionResp's direct-read body: it registers a waiter for the pc the
+// response is expected to carry, sends the packet, and blocks on the waiter
+// instead of reading the connection itself, since the dispatch loop's
+// goroutine owns all reads once it's running.
+func (g *Game) trivialActionRespAsync(pt string, packet []byte) (buf []byte, err error) {
+	var waitPc uint32
+	var waiter chan dispatchedFrame
+	if g.Encrypted {
+		waitPc = g.nextPc()
+		waiter = g.registerWaiter(waitPc)
+	}
+
+	err = g.write(packet)
+	if err != nil {
+		if waiter != nil {
+			g.takeWaiter(waitPc)
+		}
+		return nil, err
+	}
+
+	if waiter == nil {
+		_, buf, err = g.read(false)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		select {
+		case frame := <-waiter:
+			if frame.err != nil {
+				return nil, frame.err
+			}
+			buf = frame.buf
+		case <-time.After(g.joinOpts.responseTimeout()):
+			g.takeWaiter(waitPc)
+			return nil, errors.New("vikebot: timed out waiting for server response")
+		}
+	}
+
+	var resp response
+	err = g.decodePacket(buf, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Type != pt {
+		if resp.Error != nil && (resp.Type == "unknown" || resp.Type == "forbidden") {
+			return nil, fmt.Errorf("vikebot: %s", *resp.Error)
+		}
+		return nil, errors.New("vikebot: invalid server response. unexpected packet")
+	}
+
+	return buf, nil
+}
@@ -0,0 +1,64 @@
+package vikebot
+
+import (
+	"errors"
+	"testing"
+)
+
+func newDispatchTestGame() *Game {
+	return &Game{respWaiters: make(map[uint32]chan dispatchedFrame)}
+}
+
+func TestTakeWaiterReturnsRegisteredChannel(t *testing.T) {
+	g := newDispatchTestGame()
+	ch := g.registerWaiter(1)
+
+	got, ok := g.takeWaiter(1)
+	if !ok {
+		t.Fatal("takeWaiter(1) = false, want true")
+	}
+	if got != ch {
+		t.Fatal("takeWaiter returned a different channel than registerWaiter handed out")
+	}
+}
+
+func TestTakeWaiterIsOneShot(t *testing.T) {
+	g := newDispatchTestGame()
+	g.registerWaiter(1)
+	g.takeWaiter(1)
+
+	if _, ok := g.takeWaiter(1); ok {
+		t.Fatal("takeWaiter(1) succeeded twice, want the entry removed after the first take")
+	}
+}
+
+func TestTakeWaiterUnknownPc(t *testing.T) {
+	g := newDispatchTestGame()
+	if _, ok := g.takeWaiter(42); ok {
+		t.Fatal("takeWaiter(42) = true for a pc nothing registered, want false")
+	}
+}
+
+func TestFailWaitersDeliversToEveryPendingWaiter(t *testing.T) {
+	g := newDispatchTestGame()
+	first := g.registerWaiter(1)
+	second := g.registerWaiter(2)
+
+	wantErr := errors.New("connection reset")
+	g.failWaiters(wantErr)
+
+	for pc, ch := range map[uint32]chan dispatchedFrame{1: first, 2: second} {
+		select {
+		case frame := <-ch:
+			if frame.err != wantErr {
+				t.Errorf("pc %d: err = %v, want %v", pc, frame.err, wantErr)
+			}
+		default:
+			t.Errorf("pc %d: no frame delivered", pc)
+		}
+	}
+
+	if len(g.respWaiters) != 0 {
+		t.Errorf("respWaiters not drained: %d entries left", len(g.respWaiters))
+	}
+}
@@ -0,0 +1,130 @@
+package vikebot
+
+// EventType identifies the kind of unsolicited packet delivered through
+// Game.Events()/Game.On.
+type EventType string
+
+const (
+	// EventTypeDamage fires when the player takes damage outside of a
+	// direct Attack response, e.g. from another player's attack.
+	EventTypeDamage EventType = "damage"
+	// EventTypeDeath fires when the player dies.
+	EventTypeDeath EventType = "death"
+	// EventTypeRoundEnd fires when the current round ends.
+	EventTypeRoundEnd EventType = "roundend"
+	// EventTypeChat fires on an incoming chat message.
+	EventTypeChat EventType = "chat"
+)
+
+// EventDamage is the Obj of an EventTypeDamage packet.
+type EventDamage struct {
+	Amount int    `json:"amount" msgpack:"amount"`
+	From   string `json:"from" msgpack:"from"`
+}
+
+// EventDeath is the Obj of an EventTypeDeath packet.
+type EventDeath struct {
+	Killer string `json:"killer" msgpack:"killer"`
+}
+
+// EventRoundEnd is the Obj of an EventTypeRoundEnd packet.
+type EventRoundEnd struct {
+	Winner string `json:"winner" msgpack:"winner"`
+}
+
+// EventChat is the Obj of an EventTypeChat packet.
+type EventChat struct {
+	From    string `json:"from" msgpack:"from"`
+	Message string `json:"message" msgpack:"message"`
+}
+
+// Event is a single unsolicited packet pushed by the server outside of the
+// normal request/response flow. Exactly one of the typed fields matching
+// Type is populated; the others are nil. Raw holds the packet as received,
+// for event types not yet modeled by a typed variant.
+type Event struct {
+	Type EventType
+	Raw  []byte
+
+	Damage   *EventDamage
+	Death    *EventDeath
+	RoundEnd *EventRoundEnd
+	Chat     *EventChat
+}
+
+// Events returns the channel unsolicited server packets are pushed to. The
+// channel is closed once the underlying connection's read loop exits for
+// good: on Close, on a Reconnect that couldn't recover, or on any other
+// read error.
+func (g *Game) Events() <-chan Event {
+	return g.events
+}
+
+// On registers handler to be called for every future Event of the given
+// type, in addition to it being sent on Events(). Handlers run synchronously
+// on the read loop's goroutine, so a slow handler delays dispatch of
+// subsequent events; hand off long work to its own goroutine. A Player
+// action called directly inside a handler gets no response, since its
+// response can only be delivered by this same goroutine, and fails once
+// JoinOptions.ResponseTimeout has passed.
+func (g *Game) On(eventType EventType, handler func(Event)) {
+	g.handlersMu.Lock()
+	defer g.handlersMu.Unlock()
+	g.eventHandlers[eventType] = append(g.eventHandlers[eventType], handler)
+}
+
+type damageEnvelope struct {
+	Obj EventDamage `json:"obj" msgpack:"obj"`
+}
+type deathEnvelope struct {
+	Obj EventDeath `json:"obj" msgpack:"obj"`
+}
+type roundEndEnvelope struct {
+	Obj EventRoundEnd `json:"obj" msgpack:"obj"`
+}
+type chatEnvelope struct {
+	Obj EventChat `json:"obj" msgpack:"obj"`
+}
+
+// dispatchEvent decodes an unsolicited packet into an Event and delivers it
+// to both Events() and any handlers registered through On.
+func (g *Game) dispatchEvent(pt string, buf []byte) {
+	ev := Event{Type: EventType(pt), Raw: buf}
+
+	switch ev.Type {
+	case EventTypeDamage:
+		var env damageEnvelope
+		if g.decodePacket(buf, &env) == nil {
+			ev.Damage = &env.Obj
+		}
+	case EventTypeDeath:
+		var env deathEnvelope
+		if g.decodePacket(buf, &env) == nil {
+			ev.Death = &env.Obj
+		}
+	case EventTypeRoundEnd:
+		var env roundEndEnvelope
+		if g.decodePacket(buf, &env) == nil {
+			ev.RoundEnd = &env.Obj
+		}
+	case EventTypeChat:
+		var env chatEnvelope
+		if g.decodePacket(buf, &env) == nil {
+			ev.Chat = &env.Obj
+		}
+	}
+
+	select {
+	case g.events <- ev:
+	default:
+		// Nobody is reading Events() fast enough; drop rather than block
+		// the read loop, On handlers below still run.
+	}
+
+	g.handlersMu.Lock()
+	handlers := append([]func(Event){}, g.eventHandlers[ev.Type]...)
+	g.handlersMu.Unlock()
+	for _, handler := range handlers {
+		handler(ev)
+	}
+}
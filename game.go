@@ -1,7 +1,7 @@
 package vikebot
 
 import (
-	"bufio"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -12,9 +12,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 type roundInformation struct {
@@ -23,35 +24,101 @@ type roundInformation struct {
 	IPV4   string `json:"ipv4"`
 	IPV6   string `json:"ipv6"`
 	Port   int    `json:"port"`
+	// URI is the full endpoint ("tcp://1.2.3.4:9000", "wss://vikebot.com/ws")
+	// advertised by the round-entry API. Its scheme selects the Transport;
+	// it is empty for servers that haven't been upgraded yet, in which case
+	// the legacy TCP transport is used. The TCP transport always dials
+	// IPV4/Port; only the websocket transport dials URI itself.
+	URI string `json:"uri"`
 
 	Error *string `json:"error"`
 }
 
+// scheme returns the URI scheme used to pick a Transport, defaulting to
+// "tcp" when the server didn't advertise one.
+func (ri roundInformation) scheme() string {
+	idx := strings.Index(ri.URI, "://")
+	if idx < 0 {
+		return "tcp"
+	}
+	return ri.URI[:idx]
+}
+
 // Game manages all connections and authorizations for the client. Also holds
 // the state of the active player
 type Game struct {
-	conn *net.Conn
-	buf  *bufio.Reader
-	gcm  cipher.AEAD
-	pc   uint32
+	// ctx is the context JoinContext (or JoinWithOptions, via
+	// context.Background()) was called with. Every automatic reconnect
+	// triggered through g is tied to it, so cancelling ctx stops a
+	// long-running bot instead of it retrying through the full backoff
+	// policy regardless.
+	ctx context.Context
+
+	transport Transport
+	gcm       cipher.AEAD
+	pc        uint32
+	pcMu      sync.Mutex
+	codec     Codec
+
+	compressor         Compressor
+	compressionEnabled bool
+
+	worldState
+
+	authtoken     string
+	joinOpts      JoinOptions
+	pendingReplay []byte
+	reconnectMu   sync.Mutex
+	closing       bool
+
+	// dispatching is flipped once the background read loop takes over
+	// demultiplexing packets by pc/type (see dispatch.go); until then
+	// (during handshake and the initial map fetch) trivialActionResp reads
+	// directly off the connection.
+	dispatching bool
+	respWaiters map[uint32]chan dispatchedFrame
+	waitersMu   sync.Mutex
+	// done is closed by dispatchLoop when it returns, so Close can wait for
+	// the loop to stop touching gcm/Encrypted/Player before it mutates them.
+	done chan struct{}
+
+	events        chan Event
+	eventHandlers map[EventType][]func(Event)
+	handlersMu    sync.Mutex
+
+	// recorder, when set by Recorder/WrapRecorder, is fed every plaintext
+	// frame written/read so it can be replayed later through a Replayer.
+	recorder *frameRecorder
 
 	Encrypted bool
-	Player    *Player
+	Player    Player
 }
 
 // Close frees all local infos about the game and closes all remote connections
-// to any servers or APIs.
+// to any servers or APIs. It stops the background read loop, after which
+// Events() is closed.
 func (g *Game) Close() error {
-	g.buf = nil
+	g.closing = true
+
+	transport := g.transport
+	err := transport.Close()
+
+	// dispatchLoop's read/decrypt of the connection races with the field
+	// resets below - closing the transport makes its blocked ReadFrame
+	// return an error, so wait for the loop to actually observe that and
+	// exit before touching gcm/Encrypted/Player out from under it.
+	if g.dispatching {
+		<-g.done
+	}
+
 	g.gcm = nil
 	g.Encrypted = false
 	g.Player = nil
-	conn := g.conn
-	g.conn = nil
-	return (*conn).Close()
+	g.transport = nil
+	return err
 }
 
-func (g Game) encrypt(plain []byte) (cipher []byte, err error) {
+func (g *Game) encrypt(plain []byte) (cipher []byte, err error) {
 	// Generate random nonce value for this encryption round
 	nonce := make([]byte, g.gcm.NonceSize())
 	_, err = io.ReadFull(rand.Reader, nonce)
@@ -68,7 +135,7 @@ func (g Game) encrypt(plain []byte) (cipher []byte, err error) {
 	return cipherBuf, nil
 }
 
-func (g Game) encrypt64(plain []byte) (cipher64 []byte, err error) {
+func (g *Game) encrypt64(plain []byte) (cipher64 []byte, err error) {
 	// encrypt plain content
 	cipher, err := g.encrypt(plain)
 	if err != nil {
@@ -82,7 +149,7 @@ func (g Game) encrypt64(plain []byte) (cipher64 []byte, err error) {
 	return base64Cipher, nil
 }
 
-func (g Game) encryptStr(plain string) (cipher string, err error) {
+func (g *Game) encryptStr(plain string) (cipher string, err error) {
 	cipherBuf, err := g.encrypt([]byte(plain))
 	if err != nil {
 		return "", err
@@ -90,7 +157,7 @@ func (g Game) encryptStr(plain string) (cipher string, err error) {
 	return base64.RawStdEncoding.EncodeToString(cipherBuf), err
 }
 
-func (g Game) decrypt(cipher []byte) (plain []byte, err error) {
+func (g *Game) decrypt(cipher []byte) (plain []byte, err error) {
 	nonce := cipher[0:g.gcm.NonceSize()]
 	ciphertext := cipher[g.gcm.NonceSize():]
 
@@ -102,7 +169,7 @@ func (g Game) decrypt(cipher []byte) (plain []byte, err error) {
 	return
 }
 
-func (g Game) decrypt64(cipher64 []byte) (plain []byte, err error) {
+func (g *Game) decrypt64(cipher64 []byte) (plain []byte, err error) {
 	cipher := make([]byte, base64.RawStdEncoding.DecodedLen(len(cipher64)))
 	_, err = base64.RawStdEncoding.Decode(cipher, cipher64)
 	if err != nil {
@@ -112,7 +179,7 @@ func (g Game) decrypt64(cipher64 []byte) (plain []byte, err error) {
 	return g.decrypt(cipher)
 }
 
-func (g Game) decryptStr(cipher string) (plain string, err error) {
+func (g *Game) decryptStr(cipher string) (plain string, err error) {
 	cipherBuf, err := base64.RawStdEncoding.DecodeString(cipher)
 	if err != nil {
 		return "", err
@@ -125,16 +192,25 @@ func (g Game) decryptStr(cipher string) (plain string, err error) {
 }
 
 func (g *Game) write(buf []byte) error {
+	if g.recorder != nil {
+		g.recorder.record(frameOut, buf)
+	}
+
 	if g.Encrypted {
-		cipher, err := g.encrypt64(buf)
+		var err error
+		buf, err = g.seal(buf)
 		if err != nil {
 			return fmt.Errorf("vikebot: encryption failed - %s", err.Error())
 		}
-		buf = cipher
 	}
 
-	buf = append(buf, '\n')
-	_, err := (*g.conn).Write(buf)
+	err := g.transport.WriteFrame(buf)
+	if err != nil && !g.closing && g.joinOpts.AutoReconnect && isBrokenConn(err) {
+		if rerr := g.reconnect(g.ctx); rerr != nil {
+			return fmt.Errorf("vikebot: %w", rerr)
+		}
+		err = g.transport.WriteFrame(buf)
+	}
 	if err != nil {
 		return fmt.Errorf("vikebot: %s", err.Error())
 	}
@@ -142,39 +218,94 @@ func (g *Game) write(buf []byte) error {
 }
 
 func (g *Game) read(extractPt bool) (pt string, buf []byte, err error) {
-	buf, err = g.buf.ReadBytes('\n')
+	if g.pendingReplay != nil {
+		buf = g.pendingReplay
+		g.pendingReplay = nil
+		if g.recorder != nil {
+			g.recorder.record(frameIn, buf)
+		}
+		if !extractPt {
+			return "", buf, nil
+		}
+		var t typePacket
+		err = g.decodePacket(buf, &t)
+		if err != nil {
+			return "", nil, err
+		}
+		return t.Type, buf, nil
+	}
+
+	buf, err = g.transport.ReadFrame()
+	if err != nil && !g.closing && g.joinOpts.AutoReconnect && isBrokenConn(err) {
+		if rerr := g.reconnect(g.ctx); rerr != nil {
+			return "", nil, fmt.Errorf("vikebot: %w", rerr)
+		}
+		buf, err = g.transport.ReadFrame()
+	}
 	if err != nil {
 		return "", nil, err
 	}
 
 	if g.Encrypted {
-		buf = buf[:len(buf)-1]
-		plain, err := g.decrypt64(buf)
+		plain, err := g.open(buf)
 		if err != nil {
 			return "", nil, fmt.Errorf("vikebot: unsecure connection - %s", err.Error())
 		}
 		buf = plain
 	}
 
+	if g.recorder != nil {
+		g.recorder.record(frameIn, buf)
+	}
+
 	if !extractPt {
 		return "", buf, nil
 	}
 
 	var t typePacket
-	err = json.Unmarshal(buf, &t)
+	err = g.decodePacket(buf, &t)
 	if err != nil {
-		return "", nil, fmt.Errorf("vikebot: %s", err.Error())
+		return "", nil, err
 	}
 
 	return t.Type, buf, nil
 }
 
+// encodePacket wraps obj in the outPacket envelope and serializes it with
+// the currently negotiated Codec.
+func (g *Game) encodePacket(pt string, pc *uint32, obj interface{}) ([]byte, error) {
+	buf, err := g.codec.Encode(outPacket{Type: pt, Pc: pc, Obj: obj})
+	if err != nil {
+		return nil, fmt.Errorf("vikebot: %s", err.Error())
+	}
+	return buf, nil
+}
+
+// decodePacket deserializes buf into v using the currently negotiated
+// Codec.
+func (g *Game) decodePacket(buf []byte, v interface{}) error {
+	err := g.codec.Decode(buf, v)
+	if err != nil {
+		return fmt.Errorf("vikebot: %s", err.Error())
+	}
+	return nil
+}
+
 func (g *Game) trivialAction(pt string, packet []byte) error {
 	_, err := g.trivialActionResp(pt, packet)
 	return err
 }
 
+// trivialActionResp sends packet and waits for the matching server response.
+// Before the background read loop is running (during handshake/fetchMap) it
+// reads the connection directly; afterwards it defers to the dispatch loop
+// via trivialActionRespAsync, since the connection may also be delivering
+// unsolicited events in between.
 func (g *Game) trivialActionResp(pt string, packet []byte) (buf []byte, err error) {
+	if g.dispatching {
+		return g.trivialActionRespAsync(pt, packet)
+	}
+
 	// Send packet
 	err = g.write(packet)
 	if err != nil {
@@ -187,9 +318,9 @@ func (g *Game) trivialActionResp(pt string, packet []byte) (buf []byte, err erro
 		return nil, err
 	}
 	var resp response
-	err = json.Unmarshal(buf, &resp)
+	err = g.decodePacket(buf, &resp)
 	if err != nil {
-		return nil, fmt.Errorf("vikebot: %s", err.Error())
+		return nil, err
 	}
 
 	// Check for server errors or forbidden messages
@@ -202,10 +333,10 @@ func (g *Game) trivialActionResp(pt string, packet []byte) (buf []byte, err erro
 
 	// Check for pc increase if connection is encrypted
 	if g.Encrypted {
-		g.pc++
+		pc := g.nextPc()
 		if resp.Pc == nil {
 			return nil, errors.New("vikebot: invalid server response. missing pc")
-		} else if *resp.Pc != g.pc {
+		} else if *resp.Pc != pc {
 			return nil, errors.New("vikebot: invalid server response. pc mismatch")
 		}
 	}
@@ -213,147 +344,248 @@ func (g *Game) trivialActionResp(pt string, packet []byte) (buf []byte, err erro
 	return buf, nil
 }
 
-// Join exchanges the `authtoken` for server credentials and establishes a
-// secure connection (`AES256-GCM`) to the game-server. Afterwards it returns
-// a game object containing basic infos and the player's state.
-func Join(authtoken string) (g *Game, err error) {
+// fetchRoundInformation exchanges authtoken for round information using the
+// roundentry HTTP API. It is used by both Join and Reconnect, since a
+// reconnect has to go through the same credential exchange as an initial
+// join.
+func fetchRoundInformation(authtoken string) (ri roundInformation, err error) {
 	production := true
 
-	// Exchange authtoken for round information
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: !production},
 	}
 	httpclient := &http.Client{Transport: tr}
 	get, err := httpclient.Get("https://api.vikebot.com/v1/roundentry/connectinfo/" + authtoken)
 	if err != nil {
-		return nil, fmt.Errorf("vikebot: %s", err.Error())
+		return ri, fmt.Errorf("vikebot: %s", err.Error())
 	}
 	defer get.Body.Close()
 
-	var ri roundInformation
 	err = json.NewDecoder(get.Body).Decode(&ri)
 	if err != nil {
-		return nil, fmt.Errorf("vikebot: %s", err.Error())
+		return ri, fmt.Errorf("vikebot: %s", err.Error())
 	}
 
 	if ri.Error != nil {
-		return nil, fmt.Errorf("vikebot: %v", *ri.Error)
+		return ri, fmt.Errorf("vikebot: %v", *ri.Error)
 	}
 
-	// Get aes key and iv byte slices
+	return ri, nil
+}
+
+func newGCM(ri roundInformation) (cipher.AEAD, error) {
 	keyBuf, err := base64.StdEncoding.DecodeString(ri.AesKey)
 	if err != nil {
 		return nil, fmt.Errorf("vikebot: %s", err.Error())
 	}
 
-	// Create aesblock
 	block, err := aes.NewCipher(keyBuf)
 	if err != nil {
 		return nil, fmt.Errorf("vikebot: %s", err.Error())
 	}
-	// Create gcm cipher
+
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
 		return nil, fmt.Errorf("vikebot: %s", err.Error())
 	}
 
-	// Open connection to game server
-	client, err := net.Dial("tcp", fmt.Sprintf("%s:%d", ri.IPV4, ri.Port))
+	return gcm, nil
+}
+
+// dial opens the Transport described by ri (or g.joinOpts.Transport, if set)
+// and wires it up as g's transport, replacing whatever connection g
+// previously held.
+func (g *Game) dial(ri roundInformation) error {
+	transport, err := dialTransport(ri, g.joinOpts.Transport)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(ri)
 	if err != nil {
-		return nil, fmt.Errorf("vikebot: %s", err.Error())
+		return err
 	}
 
-	// Create game object
-	g = &Game{
-		conn: &client,
-		buf:  bufio.NewReader(client),
-		gcm:  gcm,
+	if g.transport != nil {
+		// Best-effort: the old connection is presumably already broken (dial
+		// is only called again by reconnectOnce), so there's nothing useful
+		// to do with an error here besides leaking the fd if we skip this.
+		g.transport.Close()
 	}
 
-	//
-	// Start login process
-	//
+	g.transport = transport
+	g.gcm = gcm
+	g.codec = jsonCodec{}
+	g.Encrypted = false
 
+	return nil
+}
+
+// handshake performs the login/clienthello/serverhello/agreeconn exchange
+// over g's current connection and leaves g with a fresh packet counter.
+func (g *Game) handshake(ri roundInformation) error {
 	// Login packet
-	err = g.trivialAction("login", loginPacket(ri.Ticket))
+	err := g.trivialAction("login", loginPacket(ri.Ticket))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Client hello
 	challengeBuf := make([]byte, 8)
 	_, err = io.ReadFull(rand.Reader, challengeBuf)
 	if err != nil {
-		return nil, fmt.Errorf("vikebot: %s", err.Error())
+		return fmt.Errorf("vikebot: %s", err.Error())
 	}
 	challenge := binary.BigEndian.Uint64(challengeBuf)
 	challengeStr := strconv.FormatUint(challenge, 10)
 	clienthelloCipher, err := g.encryptStr("clienthello:" + challengeStr)
 	if err != nil {
-		return nil, fmt.Errorf("vikebot: %s", err.Error())
+		return fmt.Errorf("vikebot: %s", err.Error())
 	}
 	err = g.write(clienthelloPacket(clienthelloCipher))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Server hello
 	pt, buf, err := g.read(true)
 	if err != nil {
-		return nil, err
+		return err
 	} else if pt != "serverhello" {
 		var resp response
 		err = json.Unmarshal(buf, &resp)
 		if err != nil {
-			return nil, fmt.Errorf("vikebot: %s", err.Error())
+			return fmt.Errorf("vikebot: %s", err.Error())
 		}
 		if resp.Error != nil {
-			return nil, fmt.Errorf("vikebot: %s", *resp.Error)
+			return fmt.Errorf("vikebot: %s", *resp.Error)
 		}
 	}
 	var serverhello serverhelloPacket
 	err = json.Unmarshal(buf, &serverhello)
 	if err != nil {
-		return nil, fmt.Errorf("vikebot: %s", err.Error())
+		return fmt.Errorf("vikebot: %s", err.Error())
 	}
 	if serverhello.Obj.Cipher == nil {
-		return nil, errors.New("vikebot: invalid server response serverhello.Obj.Cipher == nil")
+		return errors.New("vikebot: invalid server response serverhello.Obj.Cipher == nil")
 	}
 	plainServerhello, err := g.decryptStr(*serverhello.Obj.Cipher)
 	if err != nil {
-		return nil, err
+		return err
 	} else if plainServerhello != "serverhello:"+challengeStr {
-		return nil, fmt.Errorf("vikebot: invalid plain text - expecting 'serverhello:%s'", challengeStr)
+		return fmt.Errorf("vikebot: invalid plain text - expecting 'serverhello:%s'", challengeStr)
 	}
 
 	// Connection verified -> enable complete encryption
 	g.Encrypted = true
 
+	// Adopt whichever codec the server picked from clienthello's `codecs`
+	// list, falling back to the legacy json codec it didn't respond.
+	g.codec = jsonCodec{}
+	if serverhello.Obj.Codec != nil {
+		codec := codecByName(*serverhello.Obj.Codec)
+		g.codec = codec
+		if codec.Binary() {
+			if fs, ok := g.transport.(frameModeSetter); ok {
+				fs.setBinaryFraming(true)
+			}
+		}
+	}
+
 	// Initial pc
 	pt, buf, err = g.read(true)
 	if err != nil {
-		return nil, err
+		return err
 	} else if pt != "initialpc" {
-		return nil, errors.New("vikebot: invalid server response. expected initialpc packet")
+		return errors.New("vikebot: invalid server response. expected initialpc packet")
 	}
 	var resp response
-	err = json.Unmarshal(buf, &resp)
+	err = g.decodePacket(buf, &resp)
 	if err != nil {
-		return nil, fmt.Errorf("vikebot: %s", err.Error())
+		return err
 	}
 	if resp.Pc == nil {
-		return nil, errors.New("vikebot: invalid server response. expected pc in initialpc packet")
+		return errors.New("vikebot: invalid server response. expected pc in initialpc packet")
 	}
-	g.pc = *resp.Pc
+	g.setPc(*resp.Pc)
 
-	// Finished login process itself -> agree on connection
-	err = g.trivialAction("agreeconn", agreeconnPacket(g))
+	// Finished login process itself -> agree on connection, advertising the
+	// compression algorithms we support so the server can pick one
+	agreeBuf, err := g.trivialActionResp("agreeconn", agreeconnPacket(g))
+	if err != nil {
+		return err
+	}
+	var agreeResp response
+	err = g.decodePacket(agreeBuf, &agreeResp)
+	if err != nil {
+		return err
+	}
+	if agreeResp.Compressor != nil {
+		if compressor := compressorByName(*agreeResp.Compressor); compressor != nil {
+			g.compressor = compressor
+			g.compressionEnabled = true
+		}
+	}
+
+	return nil
+}
+
+// Join exchanges the `authtoken` for server credentials and establishes a
+// secure connection (`AES256-GCM`) to the game-server. Afterwards it returns
+// a game object containing basic infos and the player's state.
+func Join(authtoken string) (g *Game, err error) {
+	return JoinWithOptions(authtoken, JoinOptions{})
+}
+
+// JoinWithOptions is like Join but allows tuning optional behavior, such as
+// transparent reconnects, via JoinOptions.
+func JoinWithOptions(authtoken string, opts JoinOptions) (g *Game, err error) {
+	return joinContext(context.Background(), authtoken, opts)
+}
+
+// JoinContext is like JoinWithOptions but ties the join attempt (and any
+// later automatic reconnects triggered through g) to ctx, so long-running
+// bots can be shut down cleanly by cancelling ctx instead of leaking
+// goroutines in a backoff loop.
+func JoinContext(ctx context.Context, authtoken string, opts JoinOptions) (g *Game, err error) {
+	return joinContext(ctx, authtoken, opts)
+}
+
+func joinContext(ctx context.Context, authtoken string, opts JoinOptions) (g *Game, err error) {
+	ri, err := fetchRoundInformation(authtoken)
+	if err != nil {
+		return nil, err
+	}
+
+	g = &Game{
+		ctx:           ctx,
+		authtoken:     authtoken,
+		joinOpts:      opts,
+		respWaiters:   make(map[uint32]chan dispatchedFrame),
+		events:        make(chan Event, 32),
+		eventHandlers: make(map[EventType][]func(Event)),
+		done:          make(chan struct{}),
+	}
+
+	err = g.dial(ri)
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.handshake(ri)
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.fetchMap()
 	if err != nil {
 		return nil, err
 	}
 
 	// Allocate player struct
-	g.Player = &Player{g: g}
+	g.Player = &livePlayer{g: g}
+
+	g.startDispatch()
 
 	return g, nil
 }
@@ -1,10 +1,71 @@
 package vikebot
 
-// MapEntity is a struct with the width and height
-// of the map
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Point is a zero-based map coordinate, (0,0) being the north-west corner.
+type Point struct {
+	X int
+	Y int
+}
+
+// BlockEntity is the client's cached view of a single map block. Terrain is
+// populated once, from the map fetched on Join; EnemyHealth/EnemyCount are
+// refreshed by whatever the server last revealed through Watch/Radar/Scout
+// and go stale as the game world moves on without the client re-observing
+// them, so check Stale before trusting them.
+type BlockEntity struct {
+	Terrain     string
+	Walkable    bool
+	EnemyHealth *int
+	EnemyCount  *int
+
+	lastSeen time.Time
+}
+
+// Stale reports whether this block hasn't been re-observed for longer than
+// maxAge. A block that was never observed is always stale.
+func (b BlockEntity) Stale(maxAge time.Duration) bool {
+	if b.lastSeen.IsZero() {
+		return true
+	}
+	return time.Since(b.lastSeen) > maxAge
+}
+
+func (b *BlockEntity) touch() {
+	b.lastSeen = time.Now()
+}
+
+func isWalkableTerrain(terrain string) bool {
+	switch terrain {
+	case "wall", "water":
+		return false
+	default:
+		return true
+	}
+}
+
+// MapEntity is the client-side world model: the map's dimensions plus a
+// cached grid of BlockEntity built up from the terrain fetched on Join and
+// the server responses observed since.
 type MapEntity struct {
-	height int
+	mu     sync.RWMutex
 	width  int
+	height int
+	blocks [][]BlockEntity // blocks[y][x]
+}
+
+func newMapEntity(width, height int) *MapEntity {
+	blocks := make([][]BlockEntity, height)
+	for y := range blocks {
+		blocks[y] = make([]BlockEntity, width)
+	}
+	return &MapEntity{width: width, height: height, blocks: blocks}
 }
 
 // Width returns an int of the width of the map entity
@@ -18,7 +79,148 @@ func (me *MapEntity) Height() int {
 	return me.height
 }
 
-// Block is not implemented yet
+func (me *MapEntity) inBounds(p Point) bool {
+	return p.X >= 0 && p.Y >= 0 && p.Y < me.height && p.X < me.width
+}
+
+// Block returns the cached BlockEntity at (x, y), or nil if the coordinate
+// is outside the map.
 func (me *MapEntity) Block(x int, y int) *BlockEntity {
-	return nil
+	me.mu.RLock()
+	defer me.mu.RUnlock()
+
+	p := Point{X: x, Y: y}
+	if !me.inBounds(p) {
+		return nil
+	}
+	b := me.blocks[y][x]
+	return &b
+}
+
+func (me *MapEntity) setBlock(x, y int, mutate func(*BlockEntity)) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	p := Point{X: x, Y: y}
+	if !me.inBounds(p) {
+		return
+	}
+	mutate(&me.blocks[y][x])
+}
+
+// directionDeltas maps the cardinal Direction* constants to the coordinate
+// delta they move the player by.
+var directionDeltas = map[string]Point{
+	DirectionNorth: {X: 0, Y: -1},
+	DirectionEast:  {X: 1, Y: 0},
+	DirectionSouth: {X: 0, Y: 1},
+	DirectionWest:  {X: -1, Y: 0},
+}
+
+// Neighbors returns the walkable blocks directly adjacent to p.
+func (me *MapEntity) Neighbors(p Point) []Point {
+	var neighbors []Point
+	for _, delta := range directionDeltas {
+		n := Point{X: p.X + delta.X, Y: p.Y + delta.Y}
+		if b := me.Block(n.X, n.Y); b != nil && b.Walkable {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// Heuristic estimates the remaining cost between two points for Pathfind.
+// It must never overestimate the true cost or the returned path may not be
+// shortest.
+type Heuristic func(a, b Point) float64
+
+// ManhattanHeuristic is the default Heuristic used by Pathfind. It fits the
+// four cardinal-direction movement Move supports.
+func ManhattanHeuristic(a, b Point) float64 {
+	return math.Abs(float64(a.X-b.X)) + math.Abs(float64(a.Y-b.Y))
+}
+
+type pathNode struct {
+	p Point
+	f float64
+}
+
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].f < q[j].f }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
+
+// Pathfind runs A* over walkable blocks and returns the Direction steps to
+// walk from `from` to `to`. heuristic defaults to ManhattanHeuristic when
+// omitted; pass a custom one to bias the search, e.g. away from blocks with
+// a stale/unknown EnemyHealth.
+func (me *MapEntity) Pathfind(from, to Point, heuristic ...Heuristic) ([]string, error) {
+	h := ManhattanHeuristic
+	if len(heuristic) > 0 && heuristic[0] != nil {
+		h = heuristic[0]
+	}
+
+	if from == to {
+		return nil, nil
+	}
+
+	gScore := map[Point]float64{from: 0}
+	cameFrom := map[Point]Point{}
+	visited := map[Point]bool{}
+
+	queue := &pathQueue{{p: from, f: h(from, to)}}
+	heap.Init(queue)
+
+	for queue.Len() > 0 {
+		cur := heap.Pop(queue).(pathNode)
+		if visited[cur.p] {
+			continue
+		}
+		visited[cur.p] = true
+
+		if cur.p == to {
+			return me.reconstructPath(cameFrom, from, to), nil
+		}
+
+		for _, n := range me.Neighbors(cur.p) {
+			tentative := gScore[cur.p] + 1
+			if g, ok := gScore[n]; !ok || tentative < g {
+				gScore[n] = tentative
+				cameFrom[n] = cur.p
+				heap.Push(queue, pathNode{p: n, f: tentative + h(n, to)})
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("vikebot: no path from %+v to %+v", from, to)
+}
+
+func (me *MapEntity) reconstructPath(cameFrom map[Point]Point, from, to Point) []string {
+	points := []Point{to}
+	for cur := to; cur != from; {
+		prev := cameFrom[cur]
+		points = append(points, prev)
+		cur = prev
+	}
+
+	directions := make([]string, 0, len(points)-1)
+	for i := len(points) - 1; i > 0; i-- {
+		delta := Point{X: points[i-1].X - points[i].X, Y: points[i-1].Y - points[i].Y}
+		for dir, d := range directionDeltas {
+			if d == delta {
+				directions = append(directions, dir)
+				break
+			}
+		}
+	}
+	return directions
 }
@@ -0,0 +1,93 @@
+package vikebot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func buildTestMap(t *testing.T, rows []string) *MapEntity {
+	t.Helper()
+	if len(rows) == 0 {
+		t.Fatal("buildTestMap: no rows")
+	}
+	me := newMapEntity(len(rows[0]), len(rows))
+	for y, row := range rows {
+		for x, c := range row {
+			terrain := "grass"
+			if c == '#' {
+				terrain = "wall"
+			}
+			me.setBlock(x, y, func(b *BlockEntity) {
+				b.Terrain = terrain
+				b.Walkable = isWalkableTerrain(terrain)
+			})
+		}
+	}
+	return me
+}
+
+func TestPathfindStraightLine(t *testing.T) {
+	me := buildTestMap(t, []string{
+		"....",
+		"....",
+		"....",
+	})
+
+	path, err := me.Pathfind(Point{X: 0, Y: 0}, Point{X: 3, Y: 0})
+	if err != nil {
+		t.Fatalf("Pathfind: %v", err)
+	}
+	want := []string{DirectionEast, DirectionEast, DirectionEast}
+	if !reflect.DeepEqual(path, want) {
+		t.Fatalf("got %v, want %v", path, want)
+	}
+}
+
+func TestPathfindAroundWall(t *testing.T) {
+	me := buildTestMap(t, []string{
+		"...",
+		".#.",
+		"...",
+	})
+
+	path, err := me.Pathfind(Point{X: 0, Y: 0}, Point{X: 2, Y: 0})
+	if err != nil {
+		t.Fatalf("Pathfind: %v", err)
+	}
+	if len(path) != 2 {
+		t.Fatalf("expected the direct 2-step path since the wall isn't in its way, got %v", path)
+	}
+
+	path, err = me.Pathfind(Point{X: 0, Y: 1}, Point{X: 2, Y: 1})
+	if err != nil {
+		t.Fatalf("Pathfind: %v", err)
+	}
+	if len(path) != 4 {
+		t.Fatalf("expected a 4-step detour around the wall, got %v", path)
+	}
+}
+
+func TestPathfindNoPath(t *testing.T) {
+	me := buildTestMap(t, []string{
+		"#.#",
+		"#.#",
+		"#.#",
+	})
+
+	_, err := me.Pathfind(Point{X: 1, Y: 0}, Point{X: 0, Y: 0})
+	if err == nil {
+		t.Fatal("expected an error, (0,0) is walled off")
+	}
+}
+
+func TestPathfindSamePoint(t *testing.T) {
+	me := buildTestMap(t, []string{"."})
+
+	path, err := me.Pathfind(Point{X: 0, Y: 0}, Point{X: 0, Y: 0})
+	if err != nil {
+		t.Fatalf("Pathfind: %v", err)
+	}
+	if path != nil {
+		t.Fatalf("expected a nil path for from == to, got %v", path)
+	}
+}
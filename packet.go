@@ -1,19 +1,41 @@
 package vikebot
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 type typePacket struct {
-	Type string `json:"type"`
+	Type string `json:"type" msgpack:"type"`
 }
 
 type response struct {
-	Type  string  `json:"type"`
-	Pc    *uint32 `json:"pc"`
-	Error *string `json:"error"`
+	Type  string  `json:"type" msgpack:"type"`
+	Pc    *uint32 `json:"pc" msgpack:"pc"`
+	Error *string `json:"error" msgpack:"error"`
+	// Compressor is the name of the Compressor the server picked from the
+	// list advertised in agreeconn. Absent means the server doesn't
+	// support compression yet, so the connection stays uncompressed.
+	Compressor *string `json:"compressor" msgpack:"compressor"`
+}
+
+// outPacket is the generic envelope used for every packet sent once a Codec
+// has been negotiated. Obj is marshaled by whichever Codec is active, so
+// Player action packets only need to build the Go value that becomes Obj
+// instead of hand-rolling JSON.
+type outPacket struct {
+	Type string      `json:"type" msgpack:"type"`
+	Pc   *uint32     `json:"pc,omitempty" msgpack:"pc,omitempty"`
+	Obj  interface{} `json:"obj" msgpack:"obj"`
 }
 
 type serverhelloObj struct {
 	Cipher *string `json:"cipher"`
+	// Codec is the name of the Codec the server picked from the list
+	// advertised in clienthello. Absent (or unknown) means the server
+	// doesn't support codec negotiation yet, so the connection stays on
+	// the legacy json codec.
+	Codec *string `json:"codec"`
 }
 type serverhelloPacket struct {
 	Type string         `json:"type"`
@@ -25,10 +47,12 @@ func loginPacket(roundticket string) []byte {
 }
 
 func clienthelloPacket(cipher string) []byte {
-	return []byte(fmt.Sprintf(`{"type":"clienthello","obj":{"cipher":"%s"}}`, cipher))
+	codecsJSON, _ := json.Marshal(codecNames())
+	return []byte(fmt.Sprintf(`{"type":"clienthello","obj":{"cipher":"%s","codecs":%s}}`, cipher, codecsJSON))
 }
 
 func agreeconnPacket(g *Game) []byte {
-	g.pc++
-	return []byte(fmt.Sprintf(`{"type":"agreeconn","pc":%d,"obj":{}}`, g.pc))
+	pc := g.nextPc()
+	packet, _ := g.encodePacket("agreeconn", &pc, map[string]interface{}{"compressors": compressorNames()})
+	return packet
 }
@@ -1,7 +1,6 @@
 package vikebot
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 )
@@ -22,30 +21,62 @@ const (
 	DirectionWest = "west"
 )
 
-// Player represents a single controllable game entitiy (also
-// called character).
-type Player struct {
+// Player represents a single controllable game entity (also called
+// character). Both a live Game and a Replayer expose one as their Player
+// field: the Game's issues real network actions, the Replayer's serves a
+// previously recorded session back from a file - bot code written against
+// Player doesn't care which one is backing it.
+type Player interface {
+	Rotate(angle string) error
+	MustRotate(angle string)
+	Move(direction string) error
+	MustMove(direction string)
+	Attack() (enemyHealth int, err error)
+	MustAttack() (enemyHealth int)
+	Radar() (count int, err error)
+	MustRadar() (count int)
+	Watch() (healthMatrix [][]int, err error)
+	MustWatch() (healthMatrix [][]int)
+	Scout(distance int) (count int, err error)
+	MustScout(distance int) (count int)
+	Defend() error
+	MustDefend()
+	Undefend() error
+	MustUndefend()
+	GetHealth() (health int, err error)
+	MustGetHealth() (health int)
+}
+
+// livePlayer is the Player implementation backing a live Game, issuing
+// actions over the network connection.
+type livePlayer struct {
 	g *Game
 }
 
 type errorResp struct {
-	Error *string `json:"error,omitempty"`
+	Error *string `json:"error,omitempty" msgpack:"error,omitempty"`
+}
+
+type rotateObj struct {
+	Angle string `json:"angle" msgpack:"angle"`
 }
 
 // Rotate implements the function of 'sdk-wiki.vikebot.com/#rotate'
-func (p *Player) Rotate(angle string) error {
-	p.g.pc++
-	buf, err := p.g.trivialActionResp("rotate",
-		[]byte(fmt.Sprintf(`{"type":"rotate","pc":%d,"obj":{"angle":"%s"}}`, p.g.pc, angle)))
+func (p *livePlayer) Rotate(angle string) error {
+	pc := p.g.nextPc()
+	packet, err := p.g.encodePacket("rotate", &pc, rotateObj{Angle: angle})
+	if err != nil {
+		return err
+	}
+	buf, err := p.g.trivialActionResp("rotate", packet)
 	if err != nil {
 		return fmt.Errorf("vikebot: %s", err.Error())
 	}
 
 	var er errorResp
-
-	err = json.Unmarshal(buf, &er)
+	err = p.g.decodePacket(buf, &er)
 	if err != nil {
-		return fmt.Errorf("vikebot: %s", err.Error())
+		return err
 	}
 
 	if er.Error != nil {
@@ -57,41 +88,49 @@ func (p *Player) Rotate(angle string) error {
 
 // MustRotate is like `Rotate` but panics if any errors occur. It simplifies
 // calling if you aren't interested in error handling.
-func (p *Player) MustRotate(angle string) {
+func (p *livePlayer) MustRotate(angle string) {
 	err := p.Rotate(angle)
 	if err != nil {
 		panic(err)
 	}
 }
 
+type moveObj struct {
+	Direction string `json:"direction" msgpack:"direction"`
+}
+
 // Move instructs the player to run in the neighbor block specified by the
 // direction parameter. Directions are cardinal-directions and predefined
 // in package constants: `vikebot.Direction*`
-func (p *Player) Move(direction string) error {
-	p.g.pc++
-	buf, err := p.g.trivialActionResp("move",
-		[]byte(fmt.Sprintf(`{"type":"move","pc":%d,"obj":{"direction":"%s"}}`, p.g.pc, direction)))
+func (p *livePlayer) Move(direction string) error {
+	pc := p.g.nextPc()
+	packet, err := p.g.encodePacket("move", &pc, moveObj{Direction: direction})
+	if err != nil {
+		return err
+	}
+	buf, err := p.g.trivialActionResp("move", packet)
 	if err != nil {
 		return fmt.Errorf("vikebot: %s", err.Error())
 	}
 
 	var er errorResp
-
-	err = json.Unmarshal(buf, &er)
+	err = p.g.decodePacket(buf, &er)
 	if err != nil {
-		return fmt.Errorf("vikebot: %s", err.Error())
+		return err
 	}
 
 	if er.Error != nil {
 		return fmt.Errorf("vikebot: %s", *er.Error)
 	}
 
+	p.g.applyMove(direction)
+
 	return nil
 }
 
 // MustMove is like `Move` but panics if any errors occur. It simplifies
 // calling if you aren't interested in error handling.
-func (p *Player) MustMove(direction string) {
+func (p *livePlayer) MustMove(direction string) {
 	err := p.Move(direction)
 	if err != nil {
 		panic(err)
@@ -100,25 +139,28 @@ func (p *Player) MustMove(direction string) {
 
 type attackResp struct {
 	Obj *struct {
-		Health *int `json:"health"`
-	}
-	Error *string `json:"error,omitempty"`
+		Health *int `json:"health" msgpack:"health"`
+	} `json:"obj" msgpack:"obj"`
+	Error *string `json:"error,omitempty" msgpack:"error,omitempty"`
 }
 
 // Attack performs an attack into the direction the player is currently
 // looking.
-func (p *Player) Attack() (enemyHealth int, err error) {
-	p.g.pc++
-	buf, err := p.g.trivialActionResp("attack",
-		[]byte(fmt.Sprintf(`{"type":"attack","pc":%d,"obj":null}`, p.g.pc)))
+func (p *livePlayer) Attack() (enemyHealth int, err error) {
+	pc := p.g.nextPc()
+	packet, err := p.g.encodePacket("attack", &pc, nil)
+	if err != nil {
+		return -1, err
+	}
+	buf, err := p.g.trivialActionResp("attack", packet)
 	if err != nil {
 		return -1, err
 	}
 
 	var ar attackResp
-	err = json.Unmarshal(buf, &ar)
+	err = p.g.decodePacket(buf, &ar)
 	if err != nil {
-		return 0, fmt.Errorf("vikebot: %s", err.Error())
+		return 0, err
 	}
 
 	if ar.Error != nil {
@@ -134,7 +176,7 @@ func (p *Player) Attack() (enemyHealth int, err error) {
 
 // MustAttack is like `Attack` but panics if any errors occur. It simplifies
 // calling if you aren't interested in error handling.
-func (p *Player) MustAttack() (enemyHealth int) {
+func (p *livePlayer) MustAttack() (enemyHealth int) {
 	enemyHealth, err := p.Attack()
 	if err != nil {
 		panic(err)
@@ -144,25 +186,27 @@ func (p *Player) MustAttack() (enemyHealth int) {
 
 type radarResp struct {
 	Obj *struct {
-		Count *int `json:"counter"`
-	} `json:"obj"`
-	Error *string `json:"error,omitempty"`
+		Count *int `json:"counter" msgpack:"counter"`
+	} `json:"obj" msgpack:"obj"`
+	Error *string `json:"error,omitempty" msgpack:"error,omitempty"`
 }
 
 // Radar implements the function of 'sdk-wiki.vikebot.com/#radar'
-func (p *Player) Radar() (count int, err error) {
-	p.g.pc++
-
-	buf, err := p.g.trivialActionResp("radar",
-		[]byte(fmt.Sprintf(`{"type":"radar","pc":%d,"obj":null}`, p.g.pc)))
+func (p *livePlayer) Radar() (count int, err error) {
+	pc := p.g.nextPc()
+	packet, err := p.g.encodePacket("radar", &pc, nil)
+	if err != nil {
+		return 0, err
+	}
+	buf, err := p.g.trivialActionResp("radar", packet)
 	if err != nil {
 		return 0, err
 	}
 
 	var rr radarResp
-	err = json.Unmarshal(buf, &rr)
+	err = p.g.decodePacket(buf, &rr)
 	if err != nil {
-		return 0, fmt.Errorf("vikebot: %s", err.Error())
+		return 0, err
 	}
 
 	if rr.Error != nil {
@@ -173,12 +217,14 @@ func (p *Player) Radar() (count int, err error) {
 		return 0, errors.New("vikebot: invalid radar-response packet")
 	}
 
+	p.g.applyAreaCount(*rr.Obj.Count)
+
 	return *(*rr.Obj).Count, nil
 }
 
 // MustRadar is like `Radar` but panics if any errors occur. It simplifies
 // calling if you aren't interested in error handling.
-func (p *Player) MustRadar() (count int) {
+func (p *livePlayer) MustRadar() (count int) {
 	c, err := p.Radar()
 	if err != nil {
 		panic(err)
@@ -188,24 +234,26 @@ func (p *Player) MustRadar() (count int) {
 
 type watchResp struct {
 	Obj *struct {
-		HealthMatrix *[][]int `json:"health_matrix"`
-	}
-	Error *string `json:"error,omitempty"`
+		HealthMatrix *[][]int `json:"health_matrix" msgpack:"health_matrix"`
+	} `json:"obj" msgpack:"obj"`
+	Error *string `json:"error,omitempty" msgpack:"error,omitempty"`
 }
 
 // Watch implements the function of 'sdk-wiki.vikebot.com/#watch'
-func (p *Player) Watch() (healthMatrix [][]int, err error) {
-	p.g.pc++
-
-	buf, err := p.g.trivialActionResp("watch",
-		[]byte(fmt.Sprintf(`{"type":"watch","pc":%d,"obj":null}`, p.g.pc)))
+func (p *livePlayer) Watch() (healthMatrix [][]int, err error) {
+	pc := p.g.nextPc()
+	packet, err := p.g.encodePacket("watch", &pc, nil)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := p.g.trivialActionResp("watch", packet)
 	if err != nil {
 		return nil, fmt.Errorf("vikebot: %s", err.Error())
 	}
 	var wr watchResp
-	err = json.Unmarshal(buf, &wr)
+	err = p.g.decodePacket(buf, &wr)
 	if err != nil {
-		return nil, fmt.Errorf("vikebot: %s", err.Error())
+		return nil, err
 	}
 
 	if wr.Error != nil {
@@ -216,12 +264,14 @@ func (p *Player) Watch() (healthMatrix [][]int, err error) {
 		return nil, errors.New("vikebot: invalid watch-response packet")
 	}
 
+	p.g.applyWatch(*wr.Obj.HealthMatrix)
+
 	return *(*wr.Obj).HealthMatrix, nil
 }
 
 // MustWatch is like `Watch` but panics if any errors occur. It simplifies
 // calling if you aren't interested in error handling.
-func (p *Player) MustWatch() (healthMatrix [][]int) {
+func (p *livePlayer) MustWatch() (healthMatrix [][]int) {
 	hm, err := p.Watch()
 	if err != nil {
 		panic(err)
@@ -229,25 +279,32 @@ func (p *Player) MustWatch() (healthMatrix [][]int) {
 	return hm
 }
 
+type scoutObj struct {
+	Distance int `json:"distance" msgpack:"distance"`
+}
+
 type scoutResp struct {
 	Obj *struct {
-		Count *int `json:"counter"`
-	} `json:"obj"`
-	Error *string `json:"error,omitempty"`
+		Count *int `json:"counter" msgpack:"counter"`
+	} `json:"obj" msgpack:"obj"`
+	Error *string `json:"error,omitempty" msgpack:"error,omitempty"`
 }
 
 // Scout implements the function of 'sdk-wiki.vikebot.com/#scout'
-func (p *Player) Scout(distance int) (count int, err error) {
-	p.g.pc++
-	buf, err := p.g.trivialActionResp("scout",
-		[]byte(fmt.Sprintf(`{"type":"scout","pc":%d,"obj":{"distance":%d}}`, p.g.pc, distance)))
+func (p *livePlayer) Scout(distance int) (count int, err error) {
+	pc := p.g.nextPc()
+	packet, err := p.g.encodePacket("scout", &pc, scoutObj{Distance: distance})
+	if err != nil {
+		return 0, err
+	}
+	buf, err := p.g.trivialActionResp("scout", packet)
 	if err != nil {
 		return 0, err
 	}
 	var sr scoutResp
-	err = json.Unmarshal(buf, &sr)
+	err = p.g.decodePacket(buf, &sr)
 	if err != nil {
-		return 0, fmt.Errorf("vikebot: %s", err.Error())
+		return 0, err
 	}
 
 	if sr.Error != nil {
@@ -258,12 +315,14 @@ func (p *Player) Scout(distance int) (count int, err error) {
 		return 0, errors.New("vikebot: invalid scout-response packet")
 	}
 
+	p.g.applyAreaCount(*sr.Obj.Count)
+
 	return *(*sr.Obj).Count, nil
 }
 
 // MustScout is like `Scout` but panics if any errors occur. It simplifies
 // calling if you aren't interested in error handling.
-func (p *Player) MustScout(distance int) (count int) {
+func (p *livePlayer) MustScout(distance int) (count int) {
 	c, err := p.Scout(distance)
 	if err != nil {
 		panic(err)
@@ -273,15 +332,18 @@ func (p *Player) MustScout(distance int) (count int) {
 
 // Defend implements the function of
 // 'sdk-wiki.vikebot.com/#defend-and-undefend'
-func (p *Player) Defend() (err error) {
-	p.g.pc++
-	return p.g.trivialAction("defend",
-		[]byte(fmt.Sprintf(`{"type":"defend","pc":%d,"obj":null}`, p.g.pc)))
+func (p *livePlayer) Defend() (err error) {
+	pc := p.g.nextPc()
+	packet, err := p.g.encodePacket("defend", &pc, nil)
+	if err != nil {
+		return err
+	}
+	return p.g.trivialAction("defend", packet)
 }
 
 // MustDefend is like `Defend` but panics if any errors occur. It simplifies
 // calling if you aren't interested in error handling.
-func (p *Player) MustDefend() {
+func (p *livePlayer) MustDefend() {
 	err := p.Defend()
 	if err != nil {
 		panic(err)
@@ -290,15 +352,18 @@ func (p *Player) MustDefend() {
 
 // Undefend implements the function of
 // 'sdk-wiki.vikebot.com/#defend-and-undefend'
-func (p *Player) Undefend() (err error) {
-	p.g.pc++
-	return p.g.trivialAction("undefend",
-		[]byte(fmt.Sprintf(`{"type":"undefend","pc":%d,"obj":null}`, p.g.pc)))
+func (p *livePlayer) Undefend() (err error) {
+	pc := p.g.nextPc()
+	packet, err := p.g.encodePacket("undefend", &pc, nil)
+	if err != nil {
+		return err
+	}
+	return p.g.trivialAction("undefend", packet)
 }
 
 // MustUndefend is like `Undefend` but panics if any errors occur. It simplifies
 // calling if you aren't interested in error handling.
-func (p *Player) MustUndefend() {
+func (p *livePlayer) MustUndefend() {
 	err := p.Undefend()
 	if err != nil {
 		panic(err)
@@ -307,24 +372,26 @@ func (p *Player) MustUndefend() {
 
 type healthResp struct {
 	Obj *struct {
-		Value *int `json:"value"`
-	} `json:"obj"`
+		Value *int `json:"value" msgpack:"value"`
+	} `json:"obj" msgpack:"obj"`
 }
 
 // GetHealth implements the function of
 // 'sdk-wiki.vikebot.com/#GetHealth'
-func (p *Player) GetHealth() (health int, err error) {
-	p.g.pc++
-
-	buf, err := p.g.trivialActionResp("health",
-		[]byte(fmt.Sprintf(`{"type":"health","pc":%d,"obj":null}`, p.g.pc)))
+func (p *livePlayer) GetHealth() (health int, err error) {
+	pc := p.g.nextPc()
+	packet, err := p.g.encodePacket("health", &pc, nil)
+	if err != nil {
+		return 0, err
+	}
+	buf, err := p.g.trivialActionResp("health", packet)
 	if err != nil {
 		return 0, err
 	}
 	var hr healthResp
-	err = json.Unmarshal(buf, &hr)
+	err = p.g.decodePacket(buf, &hr)
 	if err != nil {
-		return 0, fmt.Errorf("vikebot: %s", err.Error())
+		return 0, err
 	}
 
 	if hr.Obj == nil || hr.Obj.Value == nil {
@@ -336,7 +403,7 @@ func (p *Player) GetHealth() (health int, err error) {
 
 // MustGetHealth is like `GetHealth` but panics if any errors occur. It simplifies
 // calling if you aren't interested in error handling.
-func (p *Player) MustGetHealth() (health int) {
+func (p *livePlayer) MustGetHealth() (health int) {
 	health, err := p.GetHealth()
 	if err != nil {
 		panic(err)
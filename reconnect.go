@@ -0,0 +1,225 @@
+package vikebot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// ErrSessionExpired is returned by Reconnect (and surfaces from write/read
+// when AutoReconnect is enabled) when the server no longer recognizes the
+// cached session. Callers have to treat the Game as unusable and start a
+// fresh Join.
+var ErrSessionExpired = errors.New("vikebot: session expired, server rejected resume")
+
+// BackoffPolicy controls the delay between successive Reconnect attempts.
+// NextBackoff is called with the zero-based attempt number and returns how
+// long to wait before trying again.
+type BackoffPolicy interface {
+	NextBackoff(attempt int) time.Duration
+}
+
+// ExponentialBackoff is the default BackoffPolicy used by Join/Reconnect. It
+// doubles Base on every attempt, capped at Max.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// NextBackoff implements the BackoffPolicy interface.
+func (b ExponentialBackoff) NextBackoff(attempt int) time.Duration {
+	d := b.Base << uint(attempt)
+	if d <= 0 || d > b.Max {
+		return b.Max
+	}
+	return d
+}
+
+func (b ExponentialBackoff) orDefault() BackoffPolicy {
+	if b.Base == 0 {
+		return ExponentialBackoff{Base: 500 * time.Millisecond, Max: 30 * time.Second}
+	}
+	return b
+}
+
+// JoinOptions configures optional behavior of JoinWithOptions/JoinContext.
+// The zero value keeps the plain Join semantics: no automatic reconnects.
+type JoinOptions struct {
+	// AutoReconnect makes write/read transparently call Reconnect whenever
+	// the underlying TCP connection breaks, instead of surfacing the
+	// network error to the caller.
+	AutoReconnect bool
+	// Backoff controls the delay between reconnect attempts. Defaults to
+	// an ExponentialBackoff of 500ms..30s when nil.
+	Backoff BackoffPolicy
+	// Transport overrides the Transport selected from the round-entry API's
+	// URI scheme. Mostly useful for tests and for callers embedding vikebot
+	// in an environment (e.g. WASM) that requires a custom dialer.
+	Transport Transport
+	// ResponseTimeout bounds how long a Player action waits for its matching
+	// response once the background read loop is dispatching packets.
+	// Defaults to 30s when zero.
+	ResponseTimeout time.Duration
+}
+
+func (o JoinOptions) responseTimeout() time.Duration {
+	if o.ResponseTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return o.ResponseTimeout
+}
+
+func (o JoinOptions) backoff() BackoffPolicy {
+	if o.Backoff != nil {
+		return o.Backoff
+	}
+	return ExponentialBackoff{}.orDefault()
+}
+
+type resumeconnResp struct {
+	Type   string  `json:"type"`
+	Pc     *uint32 `json:"pc"`
+	Replay *string `json:"replay"`
+	Error  *string `json:"error"`
+}
+
+func resumeconnPacket(roundticket string, pc uint32) []byte {
+	return []byte(fmt.Sprintf(`{"type":"resumeconn","obj":{"roundticket":"%s","pc":%d}}`, roundticket, pc))
+}
+
+// Reconnect detects that the connection to the game-server is broken, redoes
+// the HTTP round-entry exchange with the cached authtoken and resumes the
+// AEAD session on a fresh TCP connection. It preserves g.pc across the
+// reconnect by sending the server the last packet counter it acknowledged;
+// the server either replays the response the client missed (which is
+// transparently returned by the next read) or rejects the resume with
+// ErrSessionExpired if the session is gone.
+func (g *Game) Reconnect() error {
+	return g.reconnect(context.Background())
+}
+
+func (g *Game) reconnect(ctx context.Context) error {
+	if g.authtoken == "" {
+		return errors.New("vikebot: cannot reconnect a game that was not created through Join/JoinContext")
+	}
+
+	// write and read may both observe a broken connection at the same time
+	// from different goroutines (a Player action and the background
+	// dispatch loop); serialize so only one of them actually redials.
+	broken := g.transport
+	g.reconnectMu.Lock()
+	defer g.reconnectMu.Unlock()
+	if g.transport != broken {
+		// Another caller already redialed while this one waited for the lock.
+		return nil
+	}
+
+	backoff := g.joinOpts.backoff()
+	for attempt := 0; ; attempt++ {
+		err := g.reconnectOnce()
+		if err == nil {
+			return nil
+		}
+		if err == ErrSessionExpired {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff.NextBackoff(attempt)):
+		}
+	}
+}
+
+func (g *Game) reconnectOnce() error {
+	ri, err := fetchRoundInformation(g.authtoken)
+	if err != nil {
+		return err
+	}
+
+	// dial() resets g.codec/compression to the pre-negotiation defaults,
+	// since that's correct for a fresh Join. A resume isn't renegotiated
+	// (resumeconn carries no codec/compressor choice), so the session
+	// continues under whatever was already agreed on - restore it once the
+	// new transport is up.
+	prevCodec := g.codec
+	prevCompressor := g.compressor
+	prevCompressionEnabled := g.compressionEnabled
+
+	err = g.dial(ri)
+	if err != nil {
+		return err
+	}
+
+	lastPc := g.currentPc()
+	err = g.write(resumeconnPacket(ri.Ticket, lastPc))
+	if err != nil {
+		return err
+	}
+
+	_, buf, err := g.read(false)
+	if err != nil {
+		return err
+	}
+
+	var resp resumeconnResp
+	err = json.Unmarshal(buf, &resp)
+	if err != nil {
+		return fmt.Errorf("vikebot: %s", err.Error())
+	}
+
+	if resp.Type == "sessionexpired" {
+		return ErrSessionExpired
+	}
+	if resp.Type != "resumeconn" {
+		if resp.Error != nil {
+			return fmt.Errorf("vikebot: %s", *resp.Error)
+		}
+		return errors.New("vikebot: invalid server response. expected resumeconn packet")
+	}
+	if resp.Pc == nil {
+		return errors.New("vikebot: invalid server response. missing pc in resumeconn packet")
+	}
+
+	g.Encrypted = true
+	g.setPc(*resp.Pc)
+
+	g.codec = prevCodec
+	g.compressor = prevCompressor
+	g.compressionEnabled = prevCompressionEnabled
+	if prevCodec != nil && prevCodec.Binary() {
+		if fs, ok := g.transport.(frameModeSetter); ok {
+			fs.setBinaryFraming(true)
+		}
+	}
+
+	if resp.Replay != nil {
+		replay, err := g.decryptStr(*resp.Replay)
+		if err != nil {
+			return err
+		}
+		g.pendingReplay = []byte(replay)
+	}
+
+	return nil
+}
+
+func isBrokenConn(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		// The server closed the connection.
+		return true
+	}
+	return errors.Is(err, net.ErrClosed)
+}
@@ -0,0 +1,110 @@
+package vikebot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type frameDirection string
+
+const (
+	frameOut frameDirection = "out"
+	frameIn  frameDirection = "in"
+)
+
+// recordEntry is one line of a Recorder's log file. Packet re-expresses the
+// frame's plaintext (already past decryption/decompression) as plain JSON
+// regardless of the connection's negotiated Codec, so recordings stay
+// portable and diffable even when the session used msgpack on the wire.
+type recordEntry struct {
+	Direction frameDirection  `json:"direction"`
+	Timestamp time.Time       `json:"timestamp"`
+	Pc        uint32          `json:"pc,omitempty"`
+	Type      string          `json:"type"`
+	Packet    json.RawMessage `json:"packet"`
+}
+
+// frameRecorder is installed on a Game by Recorder/WrapRecorder and fed
+// every plaintext frame Game.write/read observe.
+type frameRecorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	g   *Game
+}
+
+func (r *frameRecorder) record(dir frameDirection, buf []byte) {
+	entry := recordEntry{Direction: dir, Timestamp: time.Now()}
+
+	var t typePacket
+	if r.g.decodePacket(buf, &t) == nil {
+		entry.Type = t.Type
+	}
+	var resp response
+	if r.g.decodePacket(buf, &resp) == nil && resp.Pc != nil {
+		entry.Pc = *resp.Pc
+	}
+
+	var generic interface{}
+	if r.g.decodePacket(buf, &generic) == nil {
+		if raw, err := json.Marshal(generic); err == nil {
+			entry.Packet = raw
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Best effort: a recording that fails to write to disk shouldn't take
+	// down the bot it's merely observing.
+	r.enc.Encode(entry)
+}
+
+// Recorder wraps a live Game, transparently logging every plaintext frame
+// exchanged with the server (direction, timestamp, pc, and the decoded
+// packet) to a file. The resulting log can be fed to NewReplayer to
+// reproduce the session offline, for unit-testing bot logic or diagnosing a
+// server-side bug without a live connection.
+type Recorder struct {
+	*Game
+	file *os.File
+}
+
+// NewRecorder is like Join, but also starts logging every frame exchanged
+// with the server to path. Logging starts once Join has completed, so the
+// handshake and initial map exchange Join performs are not in the log.
+func NewRecorder(path string, authtoken string) (*Recorder, error) {
+	return NewRecorderWithOptions(path, authtoken, JoinOptions{})
+}
+
+// NewRecorderWithOptions is like NewRecorder but allows tuning JoinOptions.
+func NewRecorderWithOptions(path string, authtoken string, opts JoinOptions) (*Recorder, error) {
+	g, err := JoinWithOptions(authtoken, opts)
+	if err != nil {
+		return nil, err
+	}
+	return WrapRecorder(path, g)
+}
+
+// WrapRecorder attaches recording to an already-joined Game. Frames
+// exchanged before this call are not logged.
+func WrapRecorder(path string, g *Game) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("vikebot: %s", err.Error())
+	}
+
+	g.recorder = &frameRecorder{enc: json.NewEncoder(f), g: g}
+	return &Recorder{Game: g, file: f}, nil
+}
+
+// Close stops recording and closes the underlying Game.
+func (r *Recorder) Close() error {
+	r.Game.recorder = nil
+	fileErr := r.file.Close()
+	if gameErr := r.Game.Close(); gameErr != nil {
+		return gameErr
+	}
+	return fileErr
+}
@@ -0,0 +1,331 @@
+package vikebot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrReplayExhausted is returned by a Replayer's Player once the log has no
+// more recorded actions left to serve.
+var ErrReplayExhausted = errors.New("vikebot: replay log has no more recorded actions")
+
+// Replayer reads a Recorder's log file and serves it back through the same
+// Player interface a live Game exposes, without any network connection. Its
+// Player replays recorded actions in the order they appear in the log,
+// matching each call against the next recorded request of the same type -
+// so bot code has to call Player methods in the same order the recorded
+// session did.
+type Replayer struct {
+	entries []recordEntry
+	pos     int
+
+	worldState
+
+	Player Player
+}
+
+// NewReplayer loads a log file written by a Recorder/WrapRecorder and
+// returns a Replayer ready to serve it back through Player.
+func NewReplayer(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("vikebot: %s", err.Error())
+	}
+	defer f.Close()
+
+	r := &Replayer{}
+	dec := json.NewDecoder(f)
+	for {
+		var entry recordEntry
+		err := dec.Decode(&entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("vikebot: %s", err.Error())
+		}
+		r.entries = append(r.entries, entry)
+	}
+
+	if err := r.replayMap(); err != nil {
+		return nil, err
+	}
+
+	r.Player = &replayPlayer{r: r}
+	return r, nil
+}
+
+// replayMap replays the recorded "map" response - sent by fetchMap on every
+// Join - up front, the same way joinContext populates a live Game's
+// MapEntity. A log that doesn't contain one (e.g. was truncated before the
+// map exchange, or was written by a Recorder, which only starts logging
+// once Join has completed) just leaves Map() nil.
+func (r *Replayer) replayMap() error {
+	start := r.pos
+	raw, err := r.nextPair("map")
+	if err == ErrReplayExhausted {
+		// nextPair scanned the whole log without finding a map pair; rewind
+		// so the recorded actions are still served to Player.
+		r.pos = start
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var mr mapResp
+	if err := json.Unmarshal(raw, &mr); err != nil {
+		return fmt.Errorf("vikebot: %s", err.Error())
+	}
+	me, err := buildMapEntity(mr)
+	if err != nil {
+		return err
+	}
+	r.setMapEntity(me)
+	return nil
+}
+
+// nextPair scans forward in the log for the next out/in entry pair whose
+// type is pt, mirroring what trivialActionResp sends/receives live, and
+// returns the in entry's recorded packet. Frames of any other type - e.g.
+// handshake frames - are skipped over. The server can push an unsolicited
+// event (damage/chat/...) at any time, including between a request and its
+// own response, so once the matching out entry is found, in entries whose
+// Type isn't pt are skipped too instead of being treated as the response -
+// the same filtering the live dispatch loop does by pc.
+func (r *Replayer) nextPair(pt string) (json.RawMessage, error) {
+	for r.pos < len(r.entries) {
+		out := r.entries[r.pos]
+		r.pos++
+		if out.Direction != frameOut || out.Type != pt {
+			continue
+		}
+
+		for r.pos < len(r.entries) {
+			in := r.entries[r.pos]
+			r.pos++
+			if in.Direction != frameIn {
+				return nil, fmt.Errorf("vikebot: malformed recording, expected response after %q", pt)
+			}
+			if in.Type != pt {
+				continue
+			}
+			return in.Packet, nil
+		}
+		return nil, fmt.Errorf("vikebot: malformed recording, no response recorded after %q", pt)
+	}
+	return nil, ErrReplayExhausted
+}
+
+// replayPlayer is the Player implementation backing a Replayer.
+type replayPlayer struct {
+	r *Replayer
+}
+
+func (p *replayPlayer) Rotate(angle string) error {
+	raw, err := p.r.nextPair("rotate")
+	if err != nil {
+		return err
+	}
+	var er errorResp
+	if err := json.Unmarshal(raw, &er); err != nil {
+		return fmt.Errorf("vikebot: %s", err.Error())
+	}
+	if er.Error != nil {
+		return fmt.Errorf("vikebot: %s", *er.Error)
+	}
+	return nil
+}
+
+// MustRotate is like `Rotate` but panics if any errors occur.
+func (p *replayPlayer) MustRotate(angle string) {
+	if err := p.Rotate(angle); err != nil {
+		panic(err)
+	}
+}
+
+func (p *replayPlayer) Move(direction string) error {
+	raw, err := p.r.nextPair("move")
+	if err != nil {
+		return err
+	}
+	var er errorResp
+	if err := json.Unmarshal(raw, &er); err != nil {
+		return fmt.Errorf("vikebot: %s", err.Error())
+	}
+	if er.Error != nil {
+		return fmt.Errorf("vikebot: %s", *er.Error)
+	}
+
+	p.r.applyMove(direction)
+	return nil
+}
+
+// MustMove is like `Move` but panics if any errors occur.
+func (p *replayPlayer) MustMove(direction string) {
+	if err := p.Move(direction); err != nil {
+		panic(err)
+	}
+}
+
+func (p *replayPlayer) Attack() (enemyHealth int, err error) {
+	raw, err := p.r.nextPair("attack")
+	if err != nil {
+		return -1, err
+	}
+	var ar attackResp
+	if err := json.Unmarshal(raw, &ar); err != nil {
+		return -1, fmt.Errorf("vikebot: %s", err.Error())
+	}
+	if ar.Error != nil {
+		return 0, fmt.Errorf("vikebot: %s", *ar.Error)
+	}
+	if ar.Obj == nil || ar.Obj.Health == nil {
+		return 0, errors.New("vikebot: invalid attack-response packet")
+	}
+	return *ar.Obj.Health, nil
+}
+
+// MustAttack is like `Attack` but panics if any errors occur.
+func (p *replayPlayer) MustAttack() (enemyHealth int) {
+	h, err := p.Attack()
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+func (p *replayPlayer) Radar() (count int, err error) {
+	raw, err := p.r.nextPair("radar")
+	if err != nil {
+		return 0, err
+	}
+	var rr radarResp
+	if err := json.Unmarshal(raw, &rr); err != nil {
+		return 0, fmt.Errorf("vikebot: %s", err.Error())
+	}
+	if rr.Error != nil {
+		return 0, fmt.Errorf("vikebot: %s", *rr.Error)
+	}
+	if rr.Obj == nil || rr.Obj.Count == nil {
+		return 0, errors.New("vikebot: invalid radar-response packet")
+	}
+	p.r.applyAreaCount(*rr.Obj.Count)
+	return *rr.Obj.Count, nil
+}
+
+// MustRadar is like `Radar` but panics if any errors occur.
+func (p *replayPlayer) MustRadar() (count int) {
+	c, err := p.Radar()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (p *replayPlayer) Watch() (healthMatrix [][]int, err error) {
+	raw, err := p.r.nextPair("watch")
+	if err != nil {
+		return nil, err
+	}
+	var wr watchResp
+	if err := json.Unmarshal(raw, &wr); err != nil {
+		return nil, fmt.Errorf("vikebot: %s", err.Error())
+	}
+	if wr.Error != nil {
+		return nil, fmt.Errorf("vikebot: %s", *wr.Error)
+	}
+	if wr.Obj == nil || wr.Obj.HealthMatrix == nil {
+		return nil, errors.New("vikebot: invalid watch-response packet")
+	}
+	p.r.applyWatch(*wr.Obj.HealthMatrix)
+	return *wr.Obj.HealthMatrix, nil
+}
+
+// MustWatch is like `Watch` but panics if any errors occur.
+func (p *replayPlayer) MustWatch() (healthMatrix [][]int) {
+	hm, err := p.Watch()
+	if err != nil {
+		panic(err)
+	}
+	return hm
+}
+
+func (p *replayPlayer) Scout(distance int) (count int, err error) {
+	raw, err := p.r.nextPair("scout")
+	if err != nil {
+		return 0, err
+	}
+	var sr scoutResp
+	if err := json.Unmarshal(raw, &sr); err != nil {
+		return 0, fmt.Errorf("vikebot: %s", err.Error())
+	}
+	if sr.Error != nil {
+		return 0, fmt.Errorf("vikebot: %s", *sr.Error)
+	}
+	if sr.Obj == nil || sr.Obj.Count == nil {
+		return 0, errors.New("vikebot: invalid scout-response packet")
+	}
+	p.r.applyAreaCount(*sr.Obj.Count)
+	return *sr.Obj.Count, nil
+}
+
+// MustScout is like `Scout` but panics if any errors occur.
+func (p *replayPlayer) MustScout(distance int) (count int) {
+	c, err := p.Scout(distance)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (p *replayPlayer) Defend() error {
+	_, err := p.r.nextPair("defend")
+	return err
+}
+
+// MustDefend is like `Defend` but panics if any errors occur.
+func (p *replayPlayer) MustDefend() {
+	if err := p.Defend(); err != nil {
+		panic(err)
+	}
+}
+
+func (p *replayPlayer) Undefend() error {
+	_, err := p.r.nextPair("undefend")
+	return err
+}
+
+// MustUndefend is like `Undefend` but panics if any errors occur.
+func (p *replayPlayer) MustUndefend() {
+	if err := p.Undefend(); err != nil {
+		panic(err)
+	}
+}
+
+func (p *replayPlayer) GetHealth() (health int, err error) {
+	raw, err := p.r.nextPair("health")
+	if err != nil {
+		return 0, err
+	}
+	var hr healthResp
+	if err := json.Unmarshal(raw, &hr); err != nil {
+		return 0, fmt.Errorf("vikebot: %s", err.Error())
+	}
+	if hr.Obj == nil || hr.Obj.Value == nil {
+		return 0, errors.New("vikebot: invalid health-response packet")
+	}
+	return *hr.Obj.Value, nil
+}
+
+// MustGetHealth is like `GetHealth` but panics if any errors occur.
+func (p *replayPlayer) MustGetHealth() (health int) {
+	h, err := p.GetHealth()
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
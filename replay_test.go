@@ -0,0 +1,84 @@
+package vikebot
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func packetJSON(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	return raw
+}
+
+func TestNextPairMatchesDirectResponse(t *testing.T) {
+	r := &Replayer{entries: []recordEntry{
+		{Direction: frameOut, Type: "move"},
+		{Direction: frameIn, Type: "move", Packet: packetJSON(t, errorResp{})},
+	}}
+
+	raw, err := r.nextPair("move")
+	if err != nil {
+		t.Fatalf("nextPair: %v", err)
+	}
+	var got errorResp
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+}
+
+func TestNextPairSkipsUnsolicitedEventBetweenRequestAndResponse(t *testing.T) {
+	r := &Replayer{entries: []recordEntry{
+		{Direction: frameOut, Type: "attack"},
+		{Direction: frameIn, Type: "damage", Packet: packetJSON(t, damageEnvelope{})},
+		{Direction: frameIn, Type: "attack", Packet: packetJSON(t, attackResp{})},
+	}}
+
+	raw, err := r.nextPair("attack")
+	if err != nil {
+		t.Fatalf("nextPair: %v", err)
+	}
+	var got attackResp
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+}
+
+func TestNextPairSkipsUnrelatedOutEntries(t *testing.T) {
+	r := &Replayer{entries: []recordEntry{
+		{Direction: frameOut, Type: "handshake-only-frame"},
+		{Direction: frameOut, Type: "move"},
+		{Direction: frameIn, Type: "move", Packet: packetJSON(t, errorResp{})},
+	}}
+
+	if _, err := r.nextPair("move"); err != nil {
+		t.Fatalf("nextPair: %v", err)
+	}
+}
+
+func TestNextPairExhausted(t *testing.T) {
+	r := &Replayer{entries: []recordEntry{
+		{Direction: frameOut, Type: "move"},
+		{Direction: frameIn, Type: "move", Packet: packetJSON(t, errorResp{})},
+	}}
+
+	if _, err := r.nextPair("move"); err != nil {
+		t.Fatalf("first nextPair: %v", err)
+	}
+	if _, err := r.nextPair("move"); err != ErrReplayExhausted {
+		t.Fatalf("second nextPair = %v, want ErrReplayExhausted", err)
+	}
+}
+
+func TestNextPairMalformedRecordingNoResponse(t *testing.T) {
+	r := &Replayer{entries: []recordEntry{
+		{Direction: frameOut, Type: "move"},
+	}}
+
+	if _, err := r.nextPair("move"); err == nil {
+		t.Fatal("expected an error for a request with no recorded response")
+	}
+}
@@ -0,0 +1,46 @@
+package vikebot
+
+// Transport abstracts the framed byte stream a Game communicates over,
+// mirroring the halfConnection/transport split in golang.org/x/crypto/ssh.
+// The encryption layer (Game.encrypt/decrypt) sits strictly above a
+// Transport, so every implementation only has to guarantee that a single
+// WriteFrame is observed as exactly one ReadFrame on the other end - how
+// that framing is achieved (newline-delimited bytes, websocket messages,
+// ...) is entirely up to the implementation.
+type Transport interface {
+	// ReadFrame blocks until a full frame is available and returns its raw
+	// bytes, with any transport-level delimiter already stripped.
+	ReadFrame() ([]byte, error)
+	// WriteFrame sends buf as a single frame.
+	WriteFrame(buf []byte) error
+	// Close releases the underlying connection.
+	Close() error
+}
+
+// frameModeSetter is implemented by transports whose on-the-wire delimiter
+// changes once a binary Codec is negotiated. Plain TCP has to switch from
+// newline-delimited text to length-prefixed frames; a message-based
+// transport like websocket is already binary safe and doesn't implement
+// this.
+type frameModeSetter interface {
+	setBinaryFraming(binary bool)
+}
+
+// dialTransport opens the Transport described by ri, unless override is
+// non-nil in which case it is used verbatim. The scheme of ri.URI selects
+// the implementation: "tcp" (the default, also used when ri.URI is empty
+// for servers that haven't been upgraded yet) dials the legacy
+// newline-delimited TCP+AES-GCM transport, "ws"/"wss" dials a websocket
+// transport.
+func dialTransport(ri roundInformation, override Transport) (Transport, error) {
+	if override != nil {
+		return override, nil
+	}
+
+	switch ri.scheme() {
+	case "ws", "wss":
+		return dialWebsocketTransport(ri)
+	default:
+		return dialTCPTransport(ri)
+	}
+}
@@ -0,0 +1,86 @@
+package vikebot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// maxBinaryFrameSize bounds the length prefix tcpTransport.ReadFrame will
+// honor, so a corrupted or hostile length field can't force an unbounded
+// allocation/read before the AEAD layer ever gets a chance to reject the
+// frame. Well above anything a real packet needs.
+const maxBinaryFrameSize = 16 * 1024 * 1024
+
+// tcpTransport is the TCP transport. By default it frames packets with a
+// trailing '\n', matching the original newline-delimited protocol. Once a
+// binary Codec is negotiated it switches to length-prefixed framing
+// (`[uint32 length][payload]`) via setBinaryFraming, since arbitrary binary
+// payloads can't be delimited by a byte they may legitimately contain.
+type tcpTransport struct {
+	conn   net.Conn
+	buf    *bufio.Reader
+	binary bool
+}
+
+func dialTCPTransport(ri roundInformation) (Transport, error) {
+	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", ri.IPV4, ri.Port))
+	if err != nil {
+		return nil, fmt.Errorf("vikebot: %s", err.Error())
+	}
+
+	return &tcpTransport{
+		conn: conn,
+		buf:  bufio.NewReader(conn),
+	}, nil
+}
+
+// setBinaryFraming switches the wire framing used by ReadFrame/WriteFrame.
+// It implements the frameModeSetter interface.
+func (t *tcpTransport) setBinaryFraming(binary bool) {
+	t.binary = binary
+}
+
+func (t *tcpTransport) ReadFrame() ([]byte, error) {
+	if t.binary {
+		lenBuf := make([]byte, 4)
+		_, err := io.ReadFull(t.buf, lenBuf)
+		if err != nil {
+			return nil, err
+		}
+		frameLen := binary.BigEndian.Uint32(lenBuf)
+		if frameLen > maxBinaryFrameSize {
+			return nil, fmt.Errorf("vikebot: frame length %d exceeds maximum of %d", frameLen, maxBinaryFrameSize)
+		}
+		buf := make([]byte, frameLen)
+		_, err = io.ReadFull(t.buf, buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf, nil
+	}
+
+	buf, err := t.buf.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	return buf[:len(buf)-1], nil
+}
+
+func (t *tcpTransport) WriteFrame(buf []byte) error {
+	if t.binary {
+		lenBuf := make([]byte, 4)
+		binary.BigEndian.PutUint32(lenBuf, uint32(len(buf)))
+		_, err := t.conn.Write(append(lenBuf, buf...))
+		return err
+	}
+
+	_, err := t.conn.Write(append(buf, '\n'))
+	return err
+}
+
+func (t *tcpTransport) Close() error {
+	return t.conn.Close()
+}
@@ -0,0 +1,42 @@
+package vikebot
+
+import (
+	"fmt"
+
+	"github.com/gorilla/websocket"
+)
+
+// websocketTransport lets bots join through `wss://`, either because
+// they're running as browser-hosted WASM where raw TCP sockets aren't
+// available, or because a corporate proxy only allows outbound HTTPS.
+// Encryption still happens above this transport, so a TLS-terminated
+// `wss://` connection runs end-to-end GCM the same way the TCP transport
+// does.
+type websocketTransport struct {
+	conn *websocket.Conn
+}
+
+func dialWebsocketTransport(ri roundInformation) (Transport, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(ri.URI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("vikebot: %s", err.Error())
+	}
+
+	return &websocketTransport{conn: conn}, nil
+}
+
+func (t *websocketTransport) ReadFrame() ([]byte, error) {
+	_, buf, err := t.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (t *websocketTransport) WriteFrame(buf []byte) error {
+	return t.conn.WriteMessage(websocket.BinaryMessage, buf)
+}
+
+func (t *websocketTransport) Close() error {
+	return t.conn.Close()
+}
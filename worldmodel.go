@@ -0,0 +1,165 @@
+package vikebot
+
+import (
+	"errors"
+	"sync"
+)
+
+// worldState is the player-position-relative world cache shared by a live
+// Game and a Replayer, kept up to date by Move/Watch/Radar/Scout. Embedding
+// it gives both types Map()/applyMove/applyWatch/applyAreaCount for free.
+// Player actions can run concurrently with each other from several user
+// goroutines, so mapEntity/playerPos are guarded the same way
+// MapEntity.blocks already is.
+type worldState struct {
+	mu        sync.Mutex
+	mapEntity *MapEntity
+	playerPos Point
+}
+
+// Map returns the cached world model, populated on Join/NewReplayer and kept
+// up to date by Move/Watch/Radar/Scout. It is nil until that initial
+// population has happened.
+func (w *worldState) Map() *MapEntity {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.mapEntity
+}
+
+func (w *worldState) setMapEntity(me *MapEntity) {
+	w.mu.Lock()
+	w.mapEntity = me
+	w.mu.Unlock()
+}
+
+// applyMove records the player's new position after a successful Move and
+// marks the destination block as freshly observed.
+func (w *worldState) applyMove(direction string) {
+	delta, ok := directionDeltas[direction]
+	if !ok {
+		return
+	}
+
+	w.mu.Lock()
+	w.playerPos = Point{X: w.playerPos.X + delta.X, Y: w.playerPos.Y + delta.Y}
+	pos, me := w.playerPos, w.mapEntity
+	w.mu.Unlock()
+
+	if me == nil {
+		return
+	}
+	me.setBlock(pos.X, pos.Y, func(b *BlockEntity) {
+		b.touch()
+	})
+}
+
+// applyWatch merges a Watch HealthMatrix, assumed centered on the player,
+// into the cached grid.
+func (w *worldState) applyWatch(healthMatrix [][]int) {
+	if len(healthMatrix) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	pos, me := w.playerPos, w.mapEntity
+	w.mu.Unlock()
+
+	if me == nil {
+		return
+	}
+
+	radius := len(healthMatrix) / 2
+	for dy, row := range healthMatrix {
+		for dx, health := range row {
+			health := health // local copy: EnemyHealth keeps a pointer to it
+			x := pos.X - radius + dx
+			y := pos.Y - radius + dy
+			me.setBlock(x, y, func(b *BlockEntity) {
+				if health < 0 {
+					b.EnemyHealth = nil
+				} else {
+					b.EnemyHealth = &health
+				}
+				b.touch()
+			})
+		}
+	}
+}
+
+// applyAreaCount records a Radar/Scout enemy count against the player's
+// current block, since neither response carries per-block positions.
+func (w *worldState) applyAreaCount(count int) {
+	w.mu.Lock()
+	pos, me := w.playerPos, w.mapEntity
+	w.mu.Unlock()
+
+	if me == nil {
+		return
+	}
+	me.setBlock(pos.X, pos.Y, func(b *BlockEntity) {
+		b.EnemyCount = &count
+		b.touch()
+	})
+}
+
+type mapResp struct {
+	Obj *struct {
+		Width   *int        `json:"width" msgpack:"width"`
+		Height  *int        `json:"height" msgpack:"height"`
+		Terrain *[][]string `json:"terrain" msgpack:"terrain"`
+	} `json:"obj" msgpack:"obj"`
+	Error *string `json:"error,omitempty" msgpack:"error,omitempty"`
+}
+
+// buildMapEntity turns a decoded mapResp into a populated MapEntity, shared
+// by both Game.fetchMap (live) and Replayer.replayMap (recorded).
+func buildMapEntity(mr mapResp) (*MapEntity, error) {
+	if mr.Error != nil {
+		return nil, errors.New("vikebot: " + *mr.Error)
+	}
+	if mr.Obj == nil || mr.Obj.Width == nil || mr.Obj.Height == nil {
+		return nil, errors.New("vikebot: invalid map-response packet")
+	}
+
+	me := newMapEntity(*mr.Obj.Width, *mr.Obj.Height)
+	if mr.Obj.Terrain != nil {
+		for y, row := range *mr.Obj.Terrain {
+			for x, terrain := range row {
+				me.setBlock(x, y, func(b *BlockEntity) {
+					b.Terrain = terrain
+					b.Walkable = isWalkableTerrain(terrain)
+				})
+			}
+		}
+	}
+	return me, nil
+}
+
+// fetchMap requests the map's dimensions and terrain and populates
+// g.mapEntity. It is called once by Join, after the connection is fully
+// agreed on.
+func (g *Game) fetchMap() error {
+	pc := g.nextPc()
+	packet, err := g.encodePacket("map", &pc, nil)
+	if err != nil {
+		return err
+	}
+	buf, err := g.trivialActionResp("map", packet)
+	if err != nil {
+		return err
+	}
+
+	var mr mapResp
+	err = g.decodePacket(buf, &mr)
+	if err != nil {
+		return err
+	}
+
+	me, err := buildMapEntity(mr)
+	if err != nil {
+		return err
+	}
+
+	g.setMapEntity(me)
+	return nil
+}